@@ -8,6 +8,8 @@ import (
 	consensusv1 "cosmossdk.io/api/cosmos/consensus/v1"
 	corecontext "cosmossdk.io/core/context"
 	"cosmossdk.io/core/transaction"
+	"cosmossdk.io/log"
+	"cosmossdk.io/server/v2/cometbft/handlers/oe"
 	"cosmossdk.io/server/v2/core/appmanager"
 	"cosmossdk.io/server/v2/core/mempool"
 	"cosmossdk.io/server/v2/core/store"
@@ -19,12 +21,100 @@ import (
 type AppQuerier[T transaction.Tx] interface {
 	ValidateTx(ctx context.Context, tx T, execMode corecontext.ExecMode) (appmanager.TxResult, error)
 	QueryWithState(ctx context.Context, state store.ReaderMap, request appmanager.Type) (appmanager.Type, error)
+
+	// ExecuteBlock runs the full state transition function over txs against
+	// rm. It backs optimistic execution, which runs it concurrently with the
+	// rest of ProcessProposal instead of waiting for FinalizeBlock;
+	// implementations must fork or otherwise isolate rm before executing
+	// against it, since the caller does not.
+	ExecuteBlock(ctx context.Context, rm store.ReaderMap, txs []T) (appmanager.BlockResponse, error)
 }
 
 type DefaultProposalHandler[T transaction.Tx] struct {
 	appQuerier AppQuerier[T]
 	mempool    mempool.Mempool[T]
 	txSelector TxSelector[T]
+
+	// optimisticExec speculatively executes a proposed block's txs as soon as
+	// ProcessProposal accepts them, so FinalizeBlock can reuse the result
+	// instead of re-executing the block. It is disabled by default; enable it
+	// with WithOptimisticExecution.
+	optimisticExec *oe.OptimisticExecution[T]
+
+	// voteExtDecoder and voteExtTxProvider together turn the local last
+	// commit's vote extensions into a synthetic tx prepended to every
+	// proposal whose local last commit carries votes. Both are nil unless WithVoteExtensions is passed, in which
+	// case vote extensions are simply not part of the proposal pipeline.
+	voteExtDecoder    VoteExtensionDecoder[T]
+	voteExtTxProvider VoteExtensionTxProvider[T]
+
+	// recoveryHandlers are tried in order to turn a recovered panic into an
+	// error; see WithRecoveryHandlers.
+	recoveryHandlers []RecoveryHandler
+	logger           log.Logger
+
+	// streamingListeners are notified, each off its own worker, of proposal
+	// and finalize-block events; see WithStreamingListeners.
+	streamingListeners []*streamingListener[T]
+}
+
+// Option configures a DefaultProposalHandler.
+type Option[T transaction.Tx] func(*DefaultProposalHandler[T])
+
+// WithOptimisticExecution enables optimistic execution: the proposal handler
+// begins executing a proposed block's txs in the background as soon as
+// ProcessProposal accepts it, so that a matching FinalizeBlock call can reuse
+// the cached result instead of re-executing the block. A handler with a nil
+// mempool accepts every proposal without validating it and starts no
+// optimistic execution for it.
+func WithOptimisticExecution[T transaction.Tx]() Option[T] {
+	return func(h *DefaultProposalHandler[T]) {
+		h.optimisticExec = oe.NewOptimisticExecution[T](h.appQuerier.ExecuteBlock, true)
+	}
+}
+
+// WithVoteExtensions gives the proposal handler a first-class hook for vote
+// extensions: PrepareHandler decodes and validates each vote extension in
+// the local last commit via decoder, then turns the commit into a synthetic
+// tx via provider and injects it at position 0 of the proposal whenever that
+// commit carries votes;
+// ProcessHandler strips that same tx back out before gas accounting.
+func WithVoteExtensions[T transaction.Tx](decoder VoteExtensionDecoder[T], provider VoteExtensionTxProvider[T]) Option[T] {
+	return func(h *DefaultProposalHandler[T]) {
+		h.voteExtDecoder = decoder
+		h.voteExtTxProvider = provider
+	}
+}
+
+// NewDefaultProposalHandler constructs a DefaultProposalHandler. Optimistic
+// execution is disabled unless WithOptimisticExecution is passed.
+func NewDefaultProposalHandler[T transaction.Tx](
+	appQuerier AppQuerier[T],
+	mp mempool.Mempool[T],
+	txSelector TxSelector[T],
+	opts ...Option[T],
+) *DefaultProposalHandler[T] {
+	h := &DefaultProposalHandler[T]{
+		appQuerier:     appQuerier,
+		mempool:        mp,
+		txSelector:     txSelector,
+		optimisticExec: oe.NewOptimisticExecution[T](appQuerier.ExecuteBlock, false),
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// OptimisticExecution exposes the handler's optimistic execution instance so
+// FinalizeBlock plumbing can call WaitResult with the hash of the block being
+// finalized, falling back to normal execution when it returns ok == false.
+// It is nil for handlers built as a struct literal rather than through
+// NewDefaultProposalHandler; callers must check before dereferencing.
+func (h *DefaultProposalHandler[T]) OptimisticExecution() *oe.OptimisticExecution[T] {
+	return h.optimisticExec
 }
 
 // TxSelector defines a helper type that assists in selecting transactions during
@@ -46,9 +136,23 @@ type TxSelector[T transaction.Tx] interface {
 }
 
 func (h *DefaultProposalHandler[T]) PrepareHandler() appmanager.PrepareHandler[T] {
-	return func(ctx context.Context, rm store.ReaderMap, txs []T, req proto.Message) ([]T, error) {
+	return func(ctx context.Context, rm store.ReaderMap, txs []T, req proto.Message) (result []T, err error) {
+		var abciReq *abci.RequestPrepareProposal
+		defer func() {
+			if abciReq != nil {
+				h.notifyListeners(func(l StreamingListener[T]) error {
+					return l.ListenPrepareProposal(ctx, abciReq, result, err)
+				})
+			}
+		}()
+		defer func() {
+			if recoveryErr := h.recoverPanic("PrepareHandler", recover()); recoveryErr != nil {
+				result, err = nil, recoveryErr
+			}
+		}()
 
-		abciReq, ok := req.(*abci.RequestPrepareProposal)
+		var ok bool
+		abciReq, ok = req.(*abci.RequestPrepareProposal)
 		if !ok {
 			return nil, fmt.Errorf("invalid request type: %T", req)
 		}
@@ -69,8 +173,32 @@ func (h *DefaultProposalHandler[T]) PrepareHandler() appmanager.PrepareHandler[T
 			maxBlockGas = uint64(b.MaxGas)
 		}
 
+		maxTxBytes := uint64(abciReq.MaxTxBytes)
+
 		defer h.txSelector.Clear()
 
+		// If the app uses vote extensions, turn the local last commit into a
+		// synthetic tx and reserve it position 0 of the proposal, subtracting
+		// its size from the remaining tx-selection budget. Clamp rather than
+		// let maxTxBytes underflow if the synthetic tx itself is larger than
+		// the whole budget.
+		voteExtTx, hasVoteExtTx, err := voteExtensionTx(ctx, h.appQuerier, h.voteExtDecoder, h.voteExtTxProvider, abciReq.LocalLastCommit)
+		if err != nil {
+			return nil, err
+		}
+		if hasVoteExtTx {
+			if stop := h.txSelector.SelectTxForProposal(ctx, maxTxBytes, maxBlockGas, voteExtTx); stop {
+				return h.txSelector.SelectedTxs(ctx), nil
+			}
+
+			voteExtBytes := uint64(len(voteExtTx.Bytes()))
+			if voteExtBytes >= maxTxBytes {
+				maxTxBytes = 0
+			} else {
+				maxTxBytes -= voteExtBytes
+			}
+		}
+
 		// TODO: can we assume nil mempool is NoOp?
 		// If the mempool is nil or NoOp we simply return the transactions
 		// requested from CometBFT, which, by default, should be in FIFO order.
@@ -78,7 +206,7 @@ func (h *DefaultProposalHandler[T]) PrepareHandler() appmanager.PrepareHandler[T
 		// Note, we still need to ensure the transactions returned respect req.MaxTxBytes.
 		if h.mempool == nil {
 			for _, tx := range txs {
-				stop := h.txSelector.SelectTxForProposal(ctx, uint64(abciReq.MaxTxBytes), maxBlockGas, tx)
+				stop := h.txSelector.SelectTxForProposal(ctx, maxTxBytes, maxBlockGas, tx)
 				if stop {
 					break
 				}
@@ -87,6 +215,14 @@ func (h *DefaultProposalHandler[T]) PrepareHandler() appmanager.PrepareHandler[T
 			return h.txSelector.SelectedTxs(ctx), nil
 		}
 
+		// If the injected mempool partitions itself into priority lanes, defer
+		// to lane-aware selection so each lane is scoped to its own share of
+		// the remaining budget. Existing single-mempool behavior is otherwise
+		// unaffected.
+		if laneMp, ok := h.mempool.(LaneMempool[T]); ok {
+			return h.prepareFromLanes(ctx, laneMp, txs, maxTxBytes, maxBlockGas)
+		}
+
 		iterator := h.mempool.Select(ctx, txs)
 		for iterator != nil {
 			memTx := iterator.Tx()
@@ -103,7 +239,7 @@ func (h *DefaultProposalHandler[T]) PrepareHandler() appmanager.PrepareHandler[T
 					return nil, err
 				}
 			} else {
-				stop := h.txSelector.SelectTxForProposal(ctx, uint64(abciReq.MaxTxBytes), maxBlockGas, memTx)
+				stop := h.txSelector.SelectTxForProposal(ctx, maxTxBytes, maxBlockGas, memTx)
 				if stop {
 					break
 				}
@@ -118,15 +254,29 @@ func (h *DefaultProposalHandler[T]) PrepareHandler() appmanager.PrepareHandler[T
 }
 
 func (h *DefaultProposalHandler[T]) ProcessHandler() appmanager.ProcessHandler[T] {
-	return func(ctx context.Context, txs []T, rm store.ReaderMap, req proto.Message) error {
+	return func(ctx context.Context, txs []T, rm store.ReaderMap, req proto.Message) (err error) {
+		var abciReq *abci.RequestProcessProposal
+		defer func() {
+			if abciReq != nil {
+				h.notifyListeners(func(l StreamingListener[T]) error {
+					return l.ListenProcessProposal(ctx, abciReq, txs, err)
+				})
+			}
+		}()
+		defer func() {
+			if recoveryErr := h.recoverPanic("ProcessHandler", recover()); recoveryErr != nil {
+				err = recoveryErr
+			}
+		}()
+
 		// If the mempool is nil or NoOp we simply return ACCEPT,
 		// because PrepareProposal may have included txs that could fail verification.
 		if h.mempool == nil {
 			return nil
 		}
 
-		// TODO: not using this request for now
-		_, ok := req.(*abci.RequestProcessProposal)
+		var ok bool
+		abciReq, ok = req.(*abci.RequestProcessProposal)
 		if !ok {
 			return fmt.Errorf("invalid request type: %T", req)
 		}
@@ -146,8 +296,24 @@ func (h *DefaultProposalHandler[T]) ProcessHandler() appmanager.ProcessHandler[T
 			maxBlockGas = uint64(b.MaxGas)
 		}
 
+		// Strip the synthetic vote-extension tx PrepareHandler injected at
+		// position 0, if present, before gas accounting -- it was already
+		// validated under ExecModeVoteExtension when it was built. Whether
+		// it's actually present must be checked via IsVoteExtensionTx rather
+		// than assumed from h.voteExtTxProvider alone: RequestProcessProposal
+		// carries no ExtendedCommitInfo, so a proposal built at a height
+		// without vote extensions in the local last commit never got one
+		// injected, and blindly dropping index 0 would silently discard a
+		// real tx. The unstripped txs are still what gets optimistically
+		// executed below, since the STF needs to see the vote-extension tx
+		// too.
+		gasTxs := txs
+		if h.voteExtTxProvider != nil && len(gasTxs) > 0 && h.voteExtTxProvider.IsVoteExtensionTx(gasTxs[0]) {
+			gasTxs = gasTxs[1:]
+		}
+
 		var totalTxGas uint64
-		for _, tx := range txs {
+		for _, tx := range gasTxs {
 			_, err := h.appQuerier.ValidateTx(ctx, tx, corecontext.ExecModePrepareProposal)
 			if err != nil {
 				return fmt.Errorf("failed to validate tx: %w", err)
@@ -161,6 +327,17 @@ func (h *DefaultProposalHandler[T]) ProcessHandler() appmanager.ProcessHandler[T
 			}
 		}
 
+		// The proposal is valid and will be ACCEPTed. Speculatively execute it
+		// now so a matching FinalizeBlock can reuse the result. optimisticExec
+		// is nil for handlers built as a struct literal rather than through
+		// NewDefaultProposalHandler. Detach from ctx: CometBFT cancels the
+		// ProcessProposal request context as soon as this function returns,
+		// which would otherwise abort the speculative execution immediately.
+		if h.optimisticExec != nil {
+			h.optimisticExec.Reset()
+			h.optimisticExec.Execute(context.WithoutCancel(ctx), rm, txs, abciReq.Hash)
+		}
+
 		return nil
 	}
 }
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	corecontext "cosmossdk.io/core/context"
+	"cosmossdk.io/core/transaction"
+	"cosmossdk.io/server/v2/core/mempool"
+)
+
+// Lane is one priority tier of a LaneMempool, e.g. an MEV/bundle lane, an
+// oracle lane, or the default lane. Lanes are consulted in priority order
+// during PrepareHandler, each scoped to its own share of the proposal's
+// remaining byte and gas budget.
+type Lane[T transaction.Tx] interface {
+	// Name identifies the lane for logs and metrics.
+	Name() string
+
+	// MaxBytesRatio is this lane's share, in [0, 1], of the proposal's
+	// MaxTxBytes.
+	MaxBytesRatio() float64
+
+	// MaxGasRatio is this lane's share, in [0, 1], of the block's MaxGas.
+	MaxGasRatio() float64
+
+	// Select returns an iterator over this lane's own candidate txs, scoped
+	// to whatever criteria (sender, message type, bid) defines the lane.
+	Select(ctx context.Context, txs []T) mempool.Iterator[T]
+}
+
+// LaneMempool is implemented by a mempool.Mempool[T] that partitions its txs
+// into priority lanes instead of a single ordering. PrepareHandler uses it
+// when present and falls back to plain single-mempool selection otherwise,
+// so existing mempools are unaffected.
+type LaneMempool[T transaction.Tx] interface {
+	mempool.Mempool[T]
+
+	// Lanes returns this mempool's lanes in priority order: the first lane
+	// gets first pick of the block budget, and whatever budget it leaves
+	// unused spills to the lanes after it.
+	Lanes() []Lane[T]
+}
+
+// prepareFromLanes selects txs lane by lane in priority order, scoping each
+// lane to its configured share of the remaining byte/gas budget and letting
+// whatever a lane doesn't use spill to the lanes after it, so a starving
+// low-priority lane can never consume the whole block.
+func (h *DefaultProposalHandler[T]) prepareFromLanes(
+	ctx context.Context,
+	laneMp LaneMempool[T],
+	txs []T,
+	maxTxBytes, maxBlockGas uint64,
+) ([]T, error) {
+	remainingBytes, remainingGas := maxTxBytes, maxBlockGas
+	var carryBytes, carryGas uint64
+
+	for _, lane := range laneMp.Lanes() {
+		bytesBefore, gasBefore := selectedBytesAndGas(h.txSelector.SelectedTxs(ctx))
+
+		laneBytesBudget := laneShare(maxTxBytes, lane.MaxBytesRatio(), carryBytes, remainingBytes)
+		laneGasBudget := laneShare(maxBlockGas, lane.MaxGasRatio(), carryGas, remainingGas)
+
+		bytesCeiling := bytesBefore + laneBytesBudget
+		gasCeiling := gasBefore + laneGasBudget
+
+		iterator := lane.Select(ctx, txs)
+		for iterator != nil {
+			memTx := iterator.Tx()
+
+			if _, err := h.appQuerier.ValidateTx(ctx, memTx, corecontext.ExecModePrepareProposal); err != nil {
+				if err := laneMp.Remove(memTx); err != nil && !errors.Is(err, mempool.ErrTxNotFound) {
+					return nil, err
+				}
+			} else if stop := h.txSelector.SelectTxForProposal(ctx, bytesCeiling, gasCeiling, memTx); stop {
+				break
+			}
+
+			iterator = iterator.Next()
+		}
+
+		bytesAfter, gasAfter := selectedBytesAndGas(h.txSelector.SelectedTxs(ctx))
+		bytesUsed, gasUsed := bytesAfter-bytesBefore, gasAfter-gasBefore
+
+		remainingBytes -= bytesUsed
+		remainingGas -= gasUsed
+
+		carryBytes = budgetLeftover(laneBytesBudget, bytesUsed)
+		carryGas = budgetLeftover(laneGasBudget, gasUsed)
+	}
+
+	return h.txSelector.SelectedTxs(ctx), nil
+}
+
+// laneShare returns ratio's share of total, plus carry -- whatever the
+// lane(s) before this one were allotted but didn't use -- capped to whatever
+// budget is still unallocated. Handing unused budget down as carry is what
+// lets a lane that needs less than its nominal share leave the rest for
+// lower-priority lanes instead of it going to waste.
+func laneShare(total uint64, ratio float64, carry, remaining uint64) uint64 {
+	share := uint64(float64(total)*ratio) + carry
+	if share > remaining {
+		share = remaining
+	}
+	return share
+}
+
+// budgetLeftover returns how much of budget went unused once a lane actually
+// consumed used, or 0 if the lane used its whole budget (or more, which
+// SelectTxForProposal never allows).
+func budgetLeftover(budget, used uint64) uint64 {
+	if used >= budget {
+		return 0
+	}
+	return budget - used
+}
+
+// selectedBytesAndGas sums the wire size and gas limit of the given txs, used
+// to figure out how much of a lane's budget the lane actually consumed.
+func selectedBytesAndGas[T transaction.Tx](selected []T) (bytes, gas uint64) {
+	for _, tx := range selected {
+		bytes += uint64(len(tx.Bytes()))
+		gas += tx.GetGasLimit()
+	}
+	return bytes, gas
+}
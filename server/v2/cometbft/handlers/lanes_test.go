@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	corecontext "cosmossdk.io/core/context"
+	"cosmossdk.io/core/transaction"
+	"cosmossdk.io/server/v2/core/appmanager"
+	"cosmossdk.io/server/v2/core/mempool"
+	"cosmossdk.io/server/v2/core/store"
+)
+
+// fakeTx is a minimal transaction.Tx used only to exercise lane selection.
+type fakeTx struct {
+	id       byte
+	size     int
+	gasLimit uint64
+}
+
+func (t fakeTx) Hash() [32]byte                             { var h [32]byte; h[0] = t.id; return h }
+func (t fakeTx) GetMessages() ([]transaction.Msg, error)     { return nil, nil }
+func (t fakeTx) GetSenders() ([]transaction.Identity, error) { return nil, nil }
+func (t fakeTx) GetGasLimit() uint64                         { return t.gasLimit }
+func (t fakeTx) Bytes() []byte                               { return make([]byte, t.size) }
+
+// fakeLane hands out a fixed set of txs and never removes any of them.
+type fakeLane struct {
+	name       string
+	bytesRatio float64
+	gasRatio   float64
+	txs        []fakeTx
+}
+
+func (l *fakeLane) Name() string           { return l.name }
+func (l *fakeLane) MaxBytesRatio() float64 { return l.bytesRatio }
+func (l *fakeLane) MaxGasRatio() float64   { return l.gasRatio }
+
+func (l *fakeLane) Select(_ context.Context, _ []fakeTx) mempool.Iterator[fakeTx] {
+	return newFakeIterator(l.txs)
+}
+
+type fakeIterator struct {
+	txs []fakeTx
+	idx int
+}
+
+func newFakeIterator(txs []fakeTx) mempool.Iterator[fakeTx] {
+	if len(txs) == 0 {
+		return nil
+	}
+	return &fakeIterator{txs: txs}
+}
+
+func (it *fakeIterator) Tx() fakeTx { return it.txs[it.idx] }
+
+func (it *fakeIterator) Next() mempool.Iterator[fakeTx] {
+	if it.idx+1 >= len(it.txs) {
+		return nil
+	}
+	return &fakeIterator{txs: it.txs, idx: it.idx + 1}
+}
+
+// fakeLaneMempool implements LaneMempool[fakeTx] over a fixed set of lanes.
+type fakeLaneMempool struct {
+	lanes []Lane[fakeTx]
+}
+
+func (m *fakeLaneMempool) Insert(context.Context, fakeTx) error { return nil }
+
+func (m *fakeLaneMempool) Select(ctx context.Context, txs []fakeTx) mempool.Iterator[fakeTx] {
+	return nil
+}
+
+func (m *fakeLaneMempool) Remove(fakeTx) error { return nil }
+
+func (m *fakeLaneMempool) Lanes() []Lane[fakeTx] { return m.lanes }
+
+// fakeAppQuerier always accepts txs.
+type fakeAppQuerier struct{}
+
+func (fakeAppQuerier) ValidateTx(context.Context, fakeTx, corecontext.ExecMode) (appmanager.TxResult, error) {
+	return appmanager.TxResult{}, nil
+}
+
+func (fakeAppQuerier) QueryWithState(context.Context, store.ReaderMap, appmanager.Type) (appmanager.Type, error) {
+	return nil, nil
+}
+
+func (fakeAppQuerier) ExecuteBlock(context.Context, store.ReaderMap, []fakeTx) (appmanager.BlockResponse, error) {
+	return appmanager.BlockResponse{}, nil
+}
+
+// fakeTxSelector is a bytes/gas-budget-respecting TxSelector used for tests.
+type fakeTxSelector struct {
+	selected []fakeTx
+	bytes    uint64
+	gas      uint64
+}
+
+func (s *fakeTxSelector) SelectedTxs(context.Context) []fakeTx { return append([]fakeTx{}, s.selected...) }
+
+func (s *fakeTxSelector) Clear() { s.selected, s.bytes, s.gas = nil, 0, 0 }
+
+func (s *fakeTxSelector) SelectTxForProposal(_ context.Context, maxTxBytes, maxBlockGas uint64, tx fakeTx) bool {
+	txBytes := uint64(len(tx.Bytes()))
+	if s.bytes+txBytes > maxTxBytes || (maxBlockGas > 0 && s.gas+tx.GetGasLimit() > maxBlockGas) {
+		return true
+	}
+
+	s.selected = append(s.selected, tx)
+	s.bytes += txBytes
+	s.gas += tx.GetGasLimit()
+	return false
+}
+
+// TestPrepareFromLanes_StarvingLowPriorityLaneCannotConsumeWholeBlock asserts
+// that a low-priority lane with far more candidate txs than fit is still
+// capped to its configured share of the block budget plus whatever the
+// higher-priority lane before it left unused, rather than filling the block.
+func TestPrepareFromLanes_StarvingLowPriorityLaneCannotConsumeWholeBlock(t *testing.T) {
+	const maxTxBytes, maxBlockGas = uint64(1000), uint64(1_000_000)
+
+	highPriority := &fakeLane{
+		name:       "oracle",
+		bytesRatio: 0.2,
+		gasRatio:   0.2,
+		txs: []fakeTx{
+			{id: 1, size: 50, gasLimit: 10_000},
+		},
+	}
+
+	starving := make([]fakeTx, 0, 100)
+	for i := 0; i < 100; i++ {
+		starving = append(starving, fakeTx{id: byte(10 + i), size: 50, gasLimit: 10_000})
+	}
+	lowPriority := &fakeLane{
+		name:       "default",
+		bytesRatio: 0.3,
+		gasRatio:   0.3,
+		txs:        starving,
+	}
+
+	h := &DefaultProposalHandler[fakeTx]{
+		appQuerier: fakeAppQuerier{},
+		txSelector: &fakeTxSelector{},
+	}
+	laneMp := &fakeLaneMempool{lanes: []Lane[fakeTx]{highPriority, lowPriority}}
+
+	selected, err := h.prepareFromLanes(context.Background(), laneMp, nil, maxTxBytes, maxBlockGas)
+	if err != nil {
+		t.Fatalf("prepareFromLanes returned error: %v", err)
+	}
+
+	var lowPrioritySelected int
+	for _, tx := range selected {
+		if tx.id != 1 {
+			lowPrioritySelected++
+		}
+	}
+
+	// The high-priority lane only used 50 of its 200-byte (0.2 * 1000) share,
+	// so that leftover 150 bytes spills down and raises the low-priority
+	// lane's effective budget above its own nominal 300-byte (0.3 * 1000)
+	// share.
+	maxLowPriorityTxs := int((float64(maxTxBytes)*lowPriority.bytesRatio + 150) / 50)
+	if lowPrioritySelected > maxLowPriorityTxs {
+		t.Fatalf("low-priority lane consumed %d txs, want at most %d (budget share plus spillover)", lowPrioritySelected, maxLowPriorityTxs)
+	}
+	if len(selected) >= len(starving) {
+		t.Fatalf("low-priority lane was allowed to consume the whole block: selected %d txs", len(selected))
+	}
+}
+
+// TestPrepareFromLanes_UnusedBudgetSpillsToLowerPriorityLanes asserts that
+// budget a higher-priority lane doesn't use becomes available to the lanes
+// after it, instead of simply being left on the table.
+func TestPrepareFromLanes_UnusedBudgetSpillsToLowerPriorityLanes(t *testing.T) {
+	const maxTxBytes, maxBlockGas = uint64(1000), uint64(1_000_000)
+
+	highPriority := &fakeLane{
+		name:       "oracle",
+		bytesRatio: 0.5,
+		gasRatio:   0.5,
+		txs: []fakeTx{
+			{id: 1, size: 50, gasLimit: 10_000},
+			{id: 2, size: 50, gasLimit: 10_000},
+		},
+	}
+
+	plenty := make([]fakeTx, 0, 30)
+	for i := 0; i < 30; i++ {
+		plenty = append(plenty, fakeTx{id: byte(10 + i), size: 50, gasLimit: 10_000})
+	}
+	lowPriority := &fakeLane{
+		name:       "default",
+		bytesRatio: 0.5,
+		gasRatio:   0.5,
+		txs:        plenty,
+	}
+
+	h := &DefaultProposalHandler[fakeTx]{
+		appQuerier: fakeAppQuerier{},
+		txSelector: &fakeTxSelector{},
+	}
+	laneMp := &fakeLaneMempool{lanes: []Lane[fakeTx]{highPriority, lowPriority}}
+
+	selected, err := h.prepareFromLanes(context.Background(), laneMp, nil, maxTxBytes, maxBlockGas)
+	if err != nil {
+		t.Fatalf("prepareFromLanes returned error: %v", err)
+	}
+
+	var lowPrioritySelected int
+	for _, tx := range selected {
+		if tx.id >= 10 {
+			lowPrioritySelected++
+		}
+	}
+
+	// highPriority only used 100 of its nominal 500-byte share, leaving 400
+	// bytes; lowPriority's nominal 500-byte share should grow to 900, i.e. 18
+	// txs, not stay capped at 500's worth (10 txs).
+	const nominalLowPriorityTxs = 10
+	if lowPrioritySelected <= nominalLowPriorityTxs {
+		t.Fatalf("low-priority lane selected %d txs, want more than its nominal share of %d -- unused budget didn't spill down", lowPrioritySelected, nominalLowPriorityTxs)
+	}
+
+	const wantLowPrioritySelected = 18
+	if lowPrioritySelected != wantLowPrioritySelected {
+		t.Fatalf("low-priority lane selected %d txs, want %d", lowPrioritySelected, wantLowPrioritySelected)
+	}
+}
@@ -0,0 +1,201 @@
+// Package oe implements optimistic execution for server/v2: eagerly running
+// a proposed block's transactions as soon as ProcessProposal accepts it, so
+// that a matching FinalizeBlock can return the cached result instead of
+// re-executing the block. It mirrors the behavior the now-removed baseapp
+// `oe` package provided.
+//
+// Execute hands the ReaderMap it's given to Executor from a background
+// goroutine while the consensus goroutine keeps running. The Executor (in
+// practice AppQuerier.ExecuteBlock) is therefore responsible for obtaining
+// its own isolated/forked view of state before touching it -- this package
+// does not fork on the caller's behalf, and passing a ReaderMap that is
+// concurrently mutated elsewhere is a data race.
+package oe
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"cosmossdk.io/core/transaction"
+	"cosmossdk.io/server/v2/core/appmanager"
+	"cosmossdk.io/server/v2/core/store"
+)
+
+// Executor runs the full state transition function for a block of
+// transactions. It is called from a goroutine that runs concurrently with
+// the rest of the consensus pipeline, so implementations (AppQuerier.
+// ExecuteBlock satisfies this signature) must fork or otherwise isolate the
+// ReaderMap they're given before executing against it.
+type Executor[T transaction.Tx] func(ctx context.Context, rm store.ReaderMap, txs []T) (appmanager.BlockResponse, error)
+
+// Metrics tracks optimistic execution outcomes. A hit means FinalizeBlock
+// matched the speculatively executed block and reused its result; an abort
+// means the speculative execution was cancelled because CometBFT finalized
+// a different block, because Reset found it still in flight when the next
+// proposal arrived, or because it failed outright.
+type Metrics struct {
+	Hits   atomic.Uint64
+	Aborts atomic.Uint64
+}
+
+// OptimisticExecution speculatively executes a proposed block's transactions
+// as soon as ProcessProposal accepts them. Reset, Execute, Abort and
+// WaitResult are all called from the single CometBFT consensus goroutine and
+// are serialized by an internal mutex; the only other goroutine involved is
+// the one spawned by Execute to run the state transition function.
+type OptimisticExecution[T transaction.Tx] struct {
+	executor Executor[T]
+	metrics  *Metrics
+	enabled  bool
+
+	mtx       sync.Mutex
+	cancel    context.CancelFunc
+	done      chan struct{}
+	blockHash []byte
+	resp      appmanager.BlockResponse
+	err       error
+}
+
+// NewOptimisticExecution constructs an OptimisticExecution. When enabled is
+// false, Execute and WaitResult become no-ops so the feature can be toggled
+// off via app configuration without touching call sites.
+func NewOptimisticExecution[T transaction.Tx](executor Executor[T], enabled bool) *OptimisticExecution[T] {
+	return &OptimisticExecution[T]{
+		executor: executor,
+		metrics:  &Metrics{},
+		enabled:  enabled,
+	}
+}
+
+// Enabled reports whether optimistic execution is turned on.
+func (oe *OptimisticExecution[T]) Enabled() bool {
+	return oe.enabled
+}
+
+// Metrics returns the hit/abort counters for this instance.
+func (oe *OptimisticExecution[T]) Metrics() *Metrics {
+	return oe.metrics
+}
+
+// Reset clears the result of any previous execution so the instance can be
+// reused for the next proposed block. It must be called before Execute.
+//
+// If the previous round's execution is still in flight -- e.g. its proposal
+// was accepted but that round lost and FinalizeBlock/WaitResult never ran --
+// Reset cancels it and waits for its goroutine to exit before returning.
+// Otherwise the orphaned goroutine would keep running the state transition
+// function against its (now stale) forked store, leaking a goroutine and
+// racing the execution Execute is about to start.
+func (oe *OptimisticExecution[T]) Reset() {
+	oe.mtx.Lock()
+	cancel, done := oe.cancel, oe.done
+	oe.cancel = nil
+	oe.done = nil
+	oe.blockHash = nil
+	oe.resp = appmanager.BlockResponse{}
+	oe.err = nil
+	oe.mtx.Unlock()
+
+	if cancel == nil {
+		return
+	}
+
+	select {
+	case <-done:
+		// The previous execution's goroutine already exited, so there's
+		// nothing in flight to cancel; Reset counts no abort for it.
+	default:
+		cancel()
+		<-done
+		oe.metrics.Aborts.Add(1)
+	}
+}
+
+// Execute kicks off speculative execution of txs against rm in a background
+// goroutine, associating the eventual result with blockHash. It is a no-op
+// when optimistic execution is disabled.
+//
+// ctx must not be the ProcessProposal request context: CometBFT cancels that
+// context as soon as ProcessProposal returns, which would abort the
+// speculative execution before FinalizeBlock ever gets a chance to use it.
+// Callers should strip cancellation (e.g. via context.WithoutCancel) while
+// keeping any values the executor needs.
+func (oe *OptimisticExecution[T]) Execute(ctx context.Context, rm store.ReaderMap, txs []T, blockHash []byte) {
+	if !oe.enabled {
+		return
+	}
+
+	oe.mtx.Lock()
+	defer oe.mtx.Unlock()
+
+	execCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	oe.cancel = cancel
+	oe.done = done
+	oe.blockHash = blockHash
+
+	go func() {
+		resp, err := oe.executor(execCtx, rm, txs)
+
+		oe.mtx.Lock()
+		oe.resp, oe.err = resp, err
+		oe.mtx.Unlock()
+
+		close(done)
+	}()
+}
+
+// WaitResult blocks until the in-flight optimistic execution finishes and
+// returns its result, provided blockHash matches the hash passed to
+// Execute. If the hashes differ -- e.g. CometBFT finalized a different
+// block than the one that was proposed -- or no execution is in flight, the
+// in-flight execution (if any) is aborted and ok is false so the caller
+// falls back to normal block execution.
+func (oe *OptimisticExecution[T]) WaitResult(blockHash []byte) (resp appmanager.BlockResponse, err error, ok bool) {
+	if !oe.enabled {
+		return appmanager.BlockResponse{}, nil, false
+	}
+
+	oe.mtx.Lock()
+	done, expected := oe.done, oe.blockHash
+	oe.mtx.Unlock()
+
+	if done == nil || !bytes.Equal(expected, blockHash) {
+		oe.Abort()
+		return appmanager.BlockResponse{}, nil, false
+	}
+
+	<-done
+
+	oe.mtx.Lock()
+	defer oe.mtx.Unlock()
+
+	if oe.err != nil {
+		oe.metrics.Aborts.Add(1)
+		return appmanager.BlockResponse{}, oe.err, false
+	}
+
+	oe.metrics.Hits.Add(1)
+	return oe.resp, nil, true
+}
+
+// Abort cancels any in-flight execution and joins its goroutine before
+// returning, guaranteeing no stale execution is still touching the forked
+// store once Abort returns.
+func (oe *OptimisticExecution[T]) Abort() {
+	oe.mtx.Lock()
+	cancel, done := oe.cancel, oe.done
+	oe.mtx.Unlock()
+
+	if cancel == nil {
+		return
+	}
+
+	cancel()
+	<-done
+
+	oe.metrics.Aborts.Add(1)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	metrics "github.com/hashicorp/go-metrics"
+
+	"cosmossdk.io/core/transaction"
+	"cosmossdk.io/log"
+	"github.com/cosmos/cosmos-sdk/telemetry"
+)
+
+// RecoveryHandler turns a value recovered from a panic into an error.
+// Handlers registered via WithRecoveryHandlers are tried in order; the first
+// to return a non-nil error wins and becomes the error
+// PrepareHandler/ProcessHandler returns to CometBFT. If every handler
+// returns nil, a generic error carrying the panic and its stack trace is
+// returned instead, modeled after the recovery chain the removed baseapp
+// recovery.go provided.
+type RecoveryHandler func(recoveryObj any) error
+
+// proposalHandlerPanicsMetricKey is the telemetry counter operators can
+// alert on, labeled by which handler (PrepareHandler/ProcessHandler)
+// recovered the panic.
+var proposalHandlerPanicsMetricKey = []string{"proposal_handler", "panics"}
+
+// WithRecoveryHandlers registers handlers that translate a recovered panic
+// into an error. PrepareHandler and ProcessHandler always recover panics --
+// even with no handlers registered, a panic becomes a returned error rather
+// than crashing the node -- registered handlers just get first say in what
+// that error looks like.
+func WithRecoveryHandlers[T transaction.Tx](handlers ...RecoveryHandler) Option[T] {
+	return func(h *DefaultProposalHandler[T]) {
+		h.recoveryHandlers = append(h.recoveryHandlers, handlers...)
+	}
+}
+
+// WithLogger sets the logger PrepareHandler/ProcessHandler use to report
+// recovered panics.
+func WithLogger[T transaction.Tx](logger log.Logger) Option[T] {
+	return func(h *DefaultProposalHandler[T]) {
+		h.logger = logger
+	}
+}
+
+// recoverPanic turns recoveryObj -- the value recover() returned, or nil if
+// there was nothing to recover -- into an error, incrementing the
+// proposal_handler.panics telemetry counter (labeled by handlerName) and
+// logging the outcome along the way. It must only be called from the
+// deferred recover() at the top of PrepareHandler/ProcessHandler.
+func (h *DefaultProposalHandler[T]) recoverPanic(handlerName string, recoveryObj any) error {
+	if recoveryObj == nil {
+		return nil
+	}
+
+	telemetry.IncrCounterWithLabels(
+		proposalHandlerPanicsMetricKey,
+		1,
+		[]metrics.Label{telemetry.NewLabel("handler", handlerName)},
+	)
+
+	var err error
+	for _, rh := range h.recoveryHandlers {
+		if err = rh(recoveryObj); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("recovered from panic in %s: %v\n%s", handlerName, recoveryObj, debug.Stack())
+	}
+
+	if h.logger != nil {
+		h.logger.Error("recovered from panic in proposal handler", "handler", handlerName, "panic", recoveryObj, "err", err)
+	}
+
+	return err
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"os"
+
+	"cosmossdk.io/core/transaction"
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+// defaultStreamingQueueSize bounds how many pending events are queued per
+// listener when no explicit size is given to WithStreamingListeners.
+const defaultStreamingQueueSize = 64
+
+// StreamingListener is notified of proposal and finalize-block events as
+// DefaultProposalHandler produces them, so indexers, rollup sequencers, and
+// external mempools can subscribe to block-building activity under
+// server/v2. It mirrors the capability the removed baseapp streaming.go
+// provided.
+type StreamingListener[T transaction.Tx] interface {
+	// ListenPrepareProposal is called once PrepareHandler has selected txs
+	// for a proposal, or failed to.
+	ListenPrepareProposal(ctx context.Context, req *abci.RequestPrepareProposal, txs []T, err error) error
+
+	// ListenProcessProposal is called once ProcessHandler has validated a
+	// proposal, or rejected it.
+	ListenProcessProposal(ctx context.Context, req *abci.RequestProcessProposal, txs []T, err error) error
+
+	// ListenFinalizeBlock is called once a block has been finalized.
+	ListenFinalizeBlock(ctx context.Context, req *abci.RequestFinalizeBlock, res *abci.ResponseFinalizeBlock) error
+
+	// ListenCommit is called once a block has been committed.
+	ListenCommit(ctx context.Context, res *abci.ResponseCommit) error
+
+	// StopNodeOnErr reports whether an error returned by this listener
+	// should crash the node rather than simply being logged.
+	StopNodeOnErr() bool
+}
+
+// streamingListener pairs a StreamingListener with its own bounded job queue
+// and single worker goroutine, so events reach that listener in the order
+// they were produced. A pool shared across listeners would let a fast
+// listener's jobs race ahead of a slow listener's backlog and deliver events
+// out of order; giving each listener its own single-worker queue keeps that
+// listener's own stream ordered while still letting listeners run
+// concurrently with each other.
+type streamingListener[T transaction.Tx] struct {
+	listener StreamingListener[T]
+	jobs     chan func()
+}
+
+func (sl *streamingListener[T]) run() {
+	for job := range sl.jobs {
+		job()
+	}
+}
+
+// WithStreamingListeners registers listeners to be notified, each off its
+// own background worker, of proposal and finalize-block events. queueSize
+// bounds how many pending events may queue for a single listener before new
+// ones are dropped (defaultStreamingQueueSize if <= 0); it is not a global
+// limit, each listener gets its own queue of this depth.
+func WithStreamingListeners[T transaction.Tx](queueSize int, listeners ...StreamingListener[T]) Option[T] {
+	if queueSize <= 0 {
+		queueSize = defaultStreamingQueueSize
+	}
+
+	return func(h *DefaultProposalHandler[T]) {
+		for _, l := range listeners {
+			sl := &streamingListener[T]{listener: l, jobs: make(chan func(), queueSize)}
+			h.streamingListeners = append(h.streamingListeners, sl)
+			go sl.run()
+		}
+	}
+}
+
+// notifyListeners schedules fn to run against every registered listener.
+// Enqueuing is non-blocking: PrepareHandler/ProcessHandler must not be
+// back-pressured by a slow or wedged listener, so an event destined for a
+// listener whose queue is already full is dropped (and logged) instead of
+// blocking the consensus goroutine that's calling notifyListeners.
+//
+// A listener whose StopNodeOnErr is true gets a logged, controlled os.Exit
+// when fn fails, rather than a panic: this runs on the listener's own
+// goroutine, which PrepareHandler/ProcessHandler's recovery defers can't see
+// or catch, so a bare panic here would just crash the process with a
+// confusing stack trace instead of a clear fatal log line.
+func (h *DefaultProposalHandler[T]) notifyListeners(fn func(l StreamingListener[T]) error) {
+	for _, sl := range h.streamingListeners {
+		sl := sl
+
+		job := func() {
+			err := fn(sl.listener)
+			if err == nil {
+				return
+			}
+
+			if sl.listener.StopNodeOnErr() {
+				if h.logger != nil {
+					h.logger.Error("stopping node: streaming listener returned a fatal error", "err", err)
+				}
+				os.Exit(1)
+			}
+
+			if h.logger != nil {
+				h.logger.Error("streaming listener returned an error", "err", err)
+			}
+		}
+
+		select {
+		case sl.jobs <- job:
+		default:
+			if h.logger != nil {
+				h.logger.Error("dropping streaming event: listener queue is full")
+			}
+		}
+	}
+}
+
+// NotifyFinalizeBlock notifies registered listeners that a block has been
+// finalized. It is exposed for the FinalizeBlock plumbing outside this
+// package to call, the same way OptimisticExecution is exposed for it.
+func (h *DefaultProposalHandler[T]) NotifyFinalizeBlock(ctx context.Context, req *abci.RequestFinalizeBlock, res *abci.ResponseFinalizeBlock) {
+	h.notifyListeners(func(l StreamingListener[T]) error {
+		return l.ListenFinalizeBlock(ctx, req, res)
+	})
+}
+
+// NotifyCommit notifies registered listeners that a block has been
+// committed. It is exposed for the Commit plumbing outside this package to
+// call, the same way OptimisticExecution is exposed for it.
+func (h *DefaultProposalHandler[T]) NotifyCommit(ctx context.Context, res *abci.ResponseCommit) {
+	h.notifyListeners(func(l StreamingListener[T]) error {
+		return l.ListenCommit(ctx, res)
+	})
+}
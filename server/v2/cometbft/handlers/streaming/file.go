@@ -0,0 +1,166 @@
+// Package streaming provides reference handlers.StreamingListener
+// implementations for server/v2.
+package streaming
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"cosmossdk.io/core/transaction"
+	"cosmossdk.io/server/v2/cometbft/handlers"
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cosmos/gogoproto/proto"
+)
+
+// defaultMaxFileSize is the size a FileListener rotates to a new file at
+// when no explicit size is given to NewFileListener.
+const defaultMaxFileSize = 128 << 20 // 128 MiB
+
+// FileListener is a reference handlers.StreamingListener[T] that writes
+// length-prefixed protobuf frames to a rotating file, mirroring the removed
+// baseapp streaming.go capability so indexers, rollup sequencers, and
+// external mempools can tail block-building events under server/v2.
+//
+// Each event writes one frame per proto message involved: the ABCI request
+// for proposal events, the request then the response for finalize events,
+// and only the response for commit events. Proposal events then add one
+// frame per selected/validated tx using the tx's own wire encoding, and a
+// final frame carrying the handler error's message if non-nil.
+type FileListener[T transaction.Tx] struct {
+	dir         string
+	maxFileSize int64
+	stopOnErr   bool
+
+	mtx     sync.Mutex
+	file    *os.File
+	written int64
+	seq     int
+}
+
+// NewFileListener creates a FileListener that writes into dir, rotating to a
+// new file before any frame that would push the current one past maxFileSize
+// bytes (defaultMaxFileSize if maxFileSize <= 0). stopOnErr is returned from StopNodeOnErr.
+func NewFileListener[T transaction.Tx](dir string, maxFileSize int64, stopOnErr bool) (*FileListener[T], error) {
+	if maxFileSize <= 0 {
+		maxFileSize = defaultMaxFileSize
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create streaming listener directory: %w", err)
+	}
+
+	l := &FileListener[T]{dir: dir, maxFileSize: maxFileSize, stopOnErr: stopOnErr}
+	if err := l.rotate(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Close closes the file the listener is currently writing to.
+func (l *FileListener[T]) Close() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return l.file.Close()
+}
+
+func (l *FileListener[T]) StopNodeOnErr() bool {
+	return l.stopOnErr
+}
+
+func (l *FileListener[T]) ListenPrepareProposal(_ context.Context, req *abci.RequestPrepareProposal, txs []T, handlerErr error) error {
+	return l.writeEvent(req, txs, handlerErr)
+}
+
+func (l *FileListener[T]) ListenProcessProposal(_ context.Context, req *abci.RequestProcessProposal, txs []T, handlerErr error) error {
+	return l.writeEvent(req, txs, handlerErr)
+}
+
+func (l *FileListener[T]) ListenFinalizeBlock(_ context.Context, req *abci.RequestFinalizeBlock, res *abci.ResponseFinalizeBlock) error {
+	if err := l.writeFrame(req); err != nil {
+		return err
+	}
+	return l.writeFrame(res)
+}
+
+func (l *FileListener[T]) ListenCommit(_ context.Context, res *abci.ResponseCommit) error {
+	return l.writeFrame(res)
+}
+
+func (l *FileListener[T]) writeEvent(req proto.Message, txs []T, handlerErr error) error {
+	if err := l.writeFrame(req); err != nil {
+		return err
+	}
+
+	for _, tx := range txs {
+		if err := l.writeRaw(tx.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	if handlerErr != nil {
+		return l.writeRaw([]byte(handlerErr.Error()))
+	}
+
+	return nil
+}
+
+func (l *FileListener[T]) writeFrame(msg proto.Message) error {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal streaming event: %w", err)
+	}
+
+	return l.writeRaw(data)
+}
+
+func (l *FileListener[T]) writeRaw(data []byte) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.written+int64(len(data))+4 > l.maxFileSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	var lenPrefix [4]byte
+	binary.BigEndian.PutUint32(lenPrefix[:], uint32(len(data)))
+
+	if _, err := l.file.Write(lenPrefix[:]); err != nil {
+		return fmt.Errorf("failed to write streaming event length prefix: %w", err)
+	}
+	if _, err := l.file.Write(data); err != nil {
+		return fmt.Errorf("failed to write streaming event: %w", err)
+	}
+
+	l.written += int64(len(data)) + 4
+	return nil
+}
+
+// rotate must be called with l.mtx held.
+func (l *FileListener[T]) rotate() error {
+	if l.file != nil {
+		if err := l.file.Close(); err != nil {
+			return fmt.Errorf("failed to close streaming listener file: %w", err)
+		}
+	}
+
+	l.seq++
+	path := filepath.Join(l.dir, fmt.Sprintf("block-events-%06d.bin", l.seq))
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open streaming listener file: %w", err)
+	}
+
+	l.file, l.written = f, 0
+	return nil
+}
+
+var _ handlers.StreamingListener[transaction.Tx] = (*FileListener[transaction.Tx])(nil)
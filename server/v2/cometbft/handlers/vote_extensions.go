@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	corecontext "cosmossdk.io/core/context"
+	"cosmossdk.io/core/transaction"
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+// VoteExtensionDecoder decodes the raw vote extension bytes CometBFT attaches
+// to a commit vote into a tx the app's STF knows how to validate. Apps that
+// don't use vote extensions can leave this unset; PrepareHandler and
+// ProcessHandler skip the synthetic vote-extension tx entirely in that case.
+type VoteExtensionDecoder[T transaction.Tx] interface {
+	DecodeVoteExtension(extension []byte) (T, error)
+}
+
+// VoteExtensionTxProvider builds the synthetic tx injected at position 0 of a
+// proposal once vote extensions have been gathered and verified. This is how
+// an app turns, e.g., a set of oracle price votes or threshold signature
+// shares into something the rest of the proposal pipeline (gas accounting,
+// mempool selection, STF) can treat like any other tx.
+type VoteExtensionTxProvider[T transaction.Tx] interface {
+	ExtendedCommitToTx(ctx context.Context, commit abci.ExtendedCommitInfo) (T, error)
+
+	// IsVoteExtensionTx reports whether tx is the synthetic tx this provider
+	// builds, so ProcessHandler can recognize and strip it symmetrically with
+	// however PrepareHandler injected it -- RequestProcessProposal carries no
+	// ExtendedCommitInfo of its own to recompute that from.
+	IsVoteExtensionTx(tx T) bool
+}
+
+// VoteExtensionsHandler implements CometBFT's ExtendVote and
+// VerifyVoteExtension ABCI++ hooks. It is a sibling of DefaultProposalHandler
+// rather than a field on it so apps that don't use vote extensions pay
+// nothing for it.
+type VoteExtensionsHandler[T transaction.Tx] struct {
+	appQuerier AppQuerier[T]
+	decoder    VoteExtensionDecoder[T]
+}
+
+// NewVoteExtensionsHandler constructs a VoteExtensionsHandler. decoder may be
+// nil if the app only implements ExtendVote and never verifies extensions
+// produced by other validators, though in practice both hooks are required
+// for CometBFT to accept the handler.
+func NewVoteExtensionsHandler[T transaction.Tx](appQuerier AppQuerier[T], decoder VoteExtensionDecoder[T]) *VoteExtensionsHandler[T] {
+	return &VoteExtensionsHandler[T]{
+		appQuerier: appQuerier,
+		decoder:    decoder,
+	}
+}
+
+// ExtendVoteHandler returns CometBFT's RequestExtendVote handler. Apps
+// override this by composing their own logic before or after calling it; the
+// default here simply satisfies the ABCI contract with an empty extension.
+func (h *VoteExtensionsHandler[T]) ExtendVoteHandler() func(ctx context.Context, req *abci.RequestExtendVote) (*abci.ResponseExtendVote, error) {
+	return func(ctx context.Context, req *abci.RequestExtendVote) (*abci.ResponseExtendVote, error) {
+		return &abci.ResponseExtendVote{}, nil
+	}
+}
+
+// VerifyVoteExtensionHandler returns CometBFT's RequestVerifyVoteExtension
+// handler. It decodes the extension via the configured VoteExtensionDecoder
+// and validates it through the app's STF under ExecModeVoteExtension, the
+// same exec mode PrepareHandler uses when assembling the synthetic
+// vote-extension tx.
+func (h *VoteExtensionsHandler[T]) VerifyVoteExtensionHandler() func(ctx context.Context, req *abci.RequestVerifyVoteExtension) (*abci.ResponseVerifyVoteExtension, error) {
+	return func(ctx context.Context, req *abci.RequestVerifyVoteExtension) (*abci.ResponseVerifyVoteExtension, error) {
+		if h.decoder == nil {
+			return &abci.ResponseVerifyVoteExtension{Status: abci.ResponseVerifyVoteExtension_ACCEPT}, nil
+		}
+
+		tx, err := h.decoder.DecodeVoteExtension(req.VoteExtension)
+		if err != nil {
+			return &abci.ResponseVerifyVoteExtension{Status: abci.ResponseVerifyVoteExtension_REJECT}, fmt.Errorf("failed to decode vote extension: %w", err)
+		}
+
+		if _, err := h.appQuerier.ValidateTx(ctx, tx, corecontext.ExecModeVoteExtension); err != nil {
+			return &abci.ResponseVerifyVoteExtension{Status: abci.ResponseVerifyVoteExtension_REJECT}, fmt.Errorf("failed to validate vote extension: %w", err)
+		}
+
+		return &abci.ResponseVerifyVoteExtension{Status: abci.ResponseVerifyVoteExtension_ACCEPT}, nil
+	}
+}
+
+// voteExtensionTx decodes and validates each of the local last commit's vote
+// extensions individually -- via decoder and appQuerier, under
+// ExecModeVoteExtension, exactly as VerifyVoteExtensionHandler validates a
+// peer's extension -- then asks txProvider to fold the whole (now-verified)
+// commit into the single synthetic tx PrepareHandler injects at position 0.
+// It returns ok == false when decoder or txProvider is unset, or the commit
+// carries no votes (e.g. the height extensions were enabled at, or any
+// height without extended commit info), so callers can treat vote
+// extensions as opt-in and ProcessHandler never has to guess whether a
+// synthetic tx is present.
+func voteExtensionTx[T transaction.Tx](
+	ctx context.Context,
+	appQuerier AppQuerier[T],
+	decoder VoteExtensionDecoder[T],
+	txProvider VoteExtensionTxProvider[T],
+	commit abci.ExtendedCommitInfo,
+) (tx T, ok bool, err error) {
+	var zero T
+
+	if decoder == nil || txProvider == nil || len(commit.Votes) == 0 {
+		return zero, false, nil
+	}
+
+	for i, vote := range commit.Votes {
+		if len(vote.VoteExtension) == 0 {
+			continue
+		}
+
+		extTx, err := decoder.DecodeVoteExtension(vote.VoteExtension)
+		if err != nil {
+			return zero, false, fmt.Errorf("failed to decode vote extension %d: %w", i, err)
+		}
+
+		if _, err := appQuerier.ValidateTx(ctx, extTx, corecontext.ExecModeVoteExtension); err != nil {
+			return zero, false, fmt.Errorf("failed to validate vote extension %d: %w", i, err)
+		}
+	}
+
+	tx, err = txProvider.ExtendedCommitToTx(ctx, commit)
+	if err != nil {
+		return zero, false, fmt.Errorf("failed to build vote extension tx: %w", err)
+	}
+
+	return tx, true, nil
+}